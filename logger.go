@@ -0,0 +1,154 @@
+package mago
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARNING"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Logger is a level-filterable logger with TAI64N-style monotonic
+// timestamps (as used by goredo's logs), so build output from parallel
+// jobs can be merged and sorted deterministically after the fact.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	json   bool
+	prefix string
+}
+
+func NewLogger(out io.Writer) *Logger {
+	return &Logger{out: out, level: LevelInfo}
+}
+
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+func (l *Logger) SetJSON(json bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.json = json
+}
+
+// SetPrefix sets a label (e.g. derived from a Cmd's name) prepended to
+// every line this Logger writes.
+func (l *Logger) SetPrefix(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefix = prefix
+}
+
+func (l *Logger) WithPrefix(prefix string) *Logger {
+	return &Logger{out: l.out, level: l.level, json: l.json, prefix: prefix}
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...any)  { l.logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...any)  { l.logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...any) { l.logf(LevelError, format, args...) }
+
+// Printf is an alias for Infof so *Logger can stand in for the log.Logger
+// call sites (Info.Printf, ...) that predate this type.
+func (l *Logger) Printf(format string, args ...any) { l.logf(LevelInfo, format, args...) }
+
+func (l *Logger) logf(level Level, format string, args ...any) {
+	l.log(level, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+func (l *Logger) log(level Level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return
+	}
+
+	ts := tai64n(time.Now())
+	if l.prefix != "" {
+		msg = l.prefix + msg
+	}
+
+	if l.json {
+		line, err := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{ts, level.String(), msg})
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(l.out, string(line))
+		return
+	}
+
+	fmt.Fprintf(l.out, "%s [%s] %s\n", ts, level.String(), msg)
+}
+
+// tai64n formats t as a TAI64N label (e.g. "@4000000000000000...") the way
+// djb's libtai and goredo do, giving fixed-width, lexically sortable
+// timestamps.
+func tai64n(t time.Time) string {
+	const tai64Epoch = 1<<62 + 10 // seconds offset used by libtai's TAI64 label
+	return fmt.Sprintf("@%016x%08x", tai64Epoch+t.Unix(), t.Nanosecond())
+}
+
+// levelWriter adapts a Logger to an io.Writer at a fixed level, so it can
+// back a standard log.Logger and keep old call sites working unchanged.
+type levelWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	w.logger.log(w.level, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// SetLevel filters the package default logger (Info/Warning/Error) down to
+// the given level and above.
+func SetLevel(level Level) {
+	defaultLogger.SetLevel(level)
+}
+
+// SetJSON switches the package default logger (Info/Warning/Error) between
+// plain-text and JSON output.
+func SetJSON(json bool) {
+	defaultLogger.SetJSON(json)
+}
+
+// SetLogger routes c's stdout/stderr through logger instead of the package
+// default, so callers can isolate a job's output (e.g. per RunParallel job).
+func (c Cmd) SetLogger(logger *Logger) {
+	c.SetStdout(levelWriter{logger, LevelInfo})
+	c.SetStderr(levelWriter{logger, LevelError})
+}
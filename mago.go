@@ -3,14 +3,11 @@ package mago
 import (
 	"fmt"
 	"io"
-	"io/fs"
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"syscall"
-	"time"
 )
 
 type LogWriter struct {
@@ -75,7 +72,13 @@ func (c Cmd) Process() *os.Process {
 }
 
 func (c Cmd) KillGroup() (ok bool) {
-	pid := c.Process().Pid
+	process := c.Process()
+	if process == nil {
+		// Never started: nothing to kill.
+		return true
+	}
+
+	pid := process.Pid
 	pgid, err := syscall.Getpgid(pid)
 	if err != nil {
 		Error.Printf("Could not get pgid of process with id: %d: %v\n", pid, err)
@@ -87,7 +90,7 @@ func (c Cmd) KillGroup() (ok bool) {
 		return false
 	}
 
-	cmd.Wait()
+	c.cmd.Wait()
 	return true
 }
 
@@ -212,95 +215,26 @@ func MaybeInstallProgram(name string, installCmd Runnable) (ok bool) {
 	return ok
 }
 
-func refreshWatchFile() (ok bool) {
-	var err error
-	watchFile, err = os.CreateTemp(os.TempDir(), "mago")
-	if err != nil {
-		Error.Printf("Could not create temp file for watch mode: %v\n", err)
-		return false
-	}
-	return true
-}
-
-func WatchFiles(patterns []string, ignoredPatterns []string) bool {
-	if watchFile == nil {
-		if !refreshWatchFile() {
-			return false
-		}
-	}
-
-	watchedFileChanged := false
-	err := filepath.Walk(".", func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		for _, ignoredPattern := range ignoredPatterns {
-			ignoredPath, err := filepath.Match(ignoredPattern, path)
-			if err == nil && ignoredPath {
-				return nil
-			}
-			ignoredName, err := filepath.Match(ignoredPattern, info.Name())
-			if err == nil && ignoredName {
-				return nil
-			}
-		}
-
-		patternMatched := false
-		for _, pattern := range patterns {
-			matched, _ := filepath.Match(pattern, info.Name())
-			if matched {
-				patternMatched = true
-				break
-			}
-		}
-		if patternMatched {
-			watchFileInfo, err := watchFile.Stat()
-			if err != nil {
-				Error.Printf("Could not stat watch file: %v\n", err)
-				return fs.SkipAll
-			}
-			if info.ModTime().After(watchFileInfo.ModTime()) {
-				watchedFileChanged = true
-				refreshWatchFile()
-				return fs.SkipAll
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		Error.Printf("Could not walk current directory: %v\n", err)
-	}
-
-	return watchedFileChanged
-}
-
-func Watch(patterns, ignoredPatterns []string, name string, args ...string) {
-	cmd, _ := CmdAsync(name, args...)
-	for {
-		if WatchFiles(patterns, ignoredPatterns) {
-			cmd.KillGroup()
-			cmd, _ = CmdAsync(name, args...)
-		}
-		time.Sleep(100 * time.Millisecond)
-	}
-}
-
 var (
+	defaultLogger *Logger
+
+	// Info, Warning and Error are thin shims over defaultLogger: existing
+	// call sites (Info.Printf, Error.Println, ...) keep working unchanged,
+	// but their output now goes through the structured, level-filterable
+	// Logger with TAI64N timestamps.
 	Info           *log.Logger
 	Warning        *log.Logger
 	Error          *log.Logger
 	InfoLogWriter  LogWriter
 	ErrorLogWriter LogWriter
-	watchFile      *os.File
 )
 
 func init() {
-	Info = log.New(os.Stdout, "[INFO] ", 0)
-	Warning = log.New(os.Stdout, "[WARNING] ", 0)
-	Error = log.New(os.Stdout, "[ERROR] ", 0)
+	defaultLogger = NewLogger(os.Stdout)
+
+	Info = log.New(levelWriter{defaultLogger, LevelInfo}, "", 0)
+	Warning = log.New(levelWriter{defaultLogger, LevelWarn}, "", 0)
+	Error = log.New(levelWriter{defaultLogger, LevelError}, "", 0)
 
 	InfoLogWriter = LogWriter{Info}
 	ErrorLogWriter = LogWriter{Error}
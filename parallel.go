@@ -0,0 +1,175 @@
+package mago
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures RunParallel.
+type ParallelOptions struct {
+	// StopOnError kills every in-flight job's process group as soon as one
+	// job fails, instead of letting the rest run to completion.
+	StopOnError bool
+}
+
+// RunParallel runs jobs across n worker goroutines, prefixing each Cmd job's
+// output with its index/name, and reports whether every job succeeded.
+func RunParallel(n int, jobs ...Runnable) bool {
+	return RunParallelOpts(n, ParallelOptions{}, jobs...)
+}
+
+// RunAll is RunParallel with n defaulted to runtime.NumCPU().
+func RunAll(jobs ...Runnable) bool {
+	return RunParallel(runtime.NumCPU(), jobs...)
+}
+
+// RunParallelOpts is RunParallel with explicit ParallelOptions, e.g. to kill
+// remaining jobs as soon as one fails.
+func RunParallelOpts(n int, opts ParallelOptions, jobs ...Runnable) bool {
+	if n < 1 {
+		n = 1
+	}
+
+	jobCh := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var mu sync.Mutex
+	ok := true
+	started := make([]bool, len(jobs))
+
+	markStarted := func(i int) {
+		mu.Lock()
+		started[i] = true
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < n; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobCh {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+
+				if !runJob(i, jobs[i], func() { markStarted(i) }) {
+					mu.Lock()
+					ok = false
+					mu.Unlock()
+
+					if opts.StopOnError {
+						stopOnce.Do(func() {
+							close(stop)
+							mu.Lock()
+							startedSnapshot := append([]bool(nil), started...)
+							mu.Unlock()
+							killInFlight(jobs, startedSnapshot)
+						})
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		jobCh <- i
+	}
+	close(jobCh)
+	wg.Wait()
+
+	return ok
+}
+
+// runJob streams a Cmd job's output through a buffered writer prefixed with
+// its index/name so interleaved parallel output stays readable. Any other
+// Runnable (e.g. PipedCmds) is run as-is, so its output is not prefixed or
+// buffered. onStarted is
+// called once the job's process has actually been started, so callers can
+// tell a queued-but-not-yet-running job apart from an in-flight one.
+func runJob(i int, job Runnable, onStarted func()) bool {
+	prefix := fmt.Sprintf("[%d/%s] ", i, jobName(job))
+
+	if cmd, isCmd := job.(Cmd); isCmd {
+		out := newPrefixWriter(InfoLogWriter, prefix)
+		errOut := newPrefixWriter(ErrorLogWriter, prefix)
+		defer out.Flush()
+		defer errOut.Flush()
+
+		cmd.SetStdout(out)
+		cmd.SetStderr(errOut)
+
+		if !cmd.Start() {
+			return false
+		}
+		onStarted()
+		return cmd.Wait() == nil
+	}
+
+	onStarted()
+	return job.Run()
+}
+
+// killInFlight kills the process group of every job that has actually been
+// started. Jobs still queued behind busy workers have a nil Process and
+// must be skipped, or Cmd.KillGroup would be called on one.
+func killInFlight(jobs []Runnable, started []bool) {
+	for i, job := range jobs {
+		if !started[i] {
+			continue
+		}
+		if cmd, isCmd := job.(Cmd); isCmd {
+			cmd.KillGroup()
+		}
+	}
+}
+
+func jobName(job Runnable) string {
+	if named, isNamed := job.(NameableRunnable); isNamed {
+		return named.Name()
+	}
+	if stringer, isStringer := job.(fmt.Stringer); isStringer {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", job)
+}
+
+// prefixWriter buffers lines and prefixes each with a job's index/name
+// before forwarding it, so interleaved parallel output stays readable.
+type prefixWriter struct {
+	dest   LogWriter
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(dest LogWriter, prefix string) *prefixWriter {
+	return &prefixWriter{dest: dest, prefix: prefix}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Incomplete line: put it back and wait for more input.
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.dest.Write([]byte(w.prefix + line))
+	}
+
+	return len(p), nil
+}
+
+func (w *prefixWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.dest.Write([]byte(w.prefix + w.buf.String() + "\n"))
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,350 @@
+package mago
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// recDir holds per-target build records and lock files, redo-style.
+const recDir = ".mago"
+
+type DepKind int
+
+const (
+	IfChange DepKind = iota
+	IfCreate
+)
+
+func (k DepKind) String() string {
+	if k == IfCreate {
+		return "ifcreate"
+	}
+	return "ifchange"
+}
+
+type Dep struct {
+	Kind DepKind
+	Path string
+}
+
+type Target struct {
+	name  string
+	deps  []Dep
+	build Runnable
+}
+
+var (
+	targetsMu sync.Mutex
+	targets   = map[string]Target{}
+	built     = map[string]bool{} // keyed by BuildUUID + target name
+)
+
+func NewTarget(name string, deps []string, build Runnable) Target {
+	t := Target{name: name, build: build}
+	for _, path := range deps {
+		t.deps = append(t.deps, Dep{Kind: IfChange, Path: path})
+	}
+
+	targetsMu.Lock()
+	targets[name] = t
+	targetsMu.Unlock()
+
+	return t
+}
+
+// IfCreate adds prerequisites that trigger a rebuild when they appear for
+// the first time, rather than when their content changes.
+func (t Target) IfCreate(paths ...string) Target {
+	for _, path := range paths {
+		t.deps = append(t.deps, Dep{Kind: IfCreate, Path: path})
+	}
+
+	targetsMu.Lock()
+	targets[t.name] = t
+	targetsMu.Unlock()
+
+	return t
+}
+
+// Redo rebuilds each named target if any of its prerequisites are missing,
+// have changed, or if the target itself has never been built. A target's
+// own prerequisites are redone first if they're targets too, and a target
+// is only ever built once per BuildUUID.
+func Redo(names ...string) bool {
+	uuid := buildUUID()
+	visiting := map[string]bool{}
+
+	ok := true
+	for _, name := range names {
+		if !redoOne(name, uuid, visiting) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// redoOne builds name if needed. visiting holds the targets currently being
+// built further up this call's recursion, so a dep cycle (A depends on B
+// depends on A, or a target depending on itself) is reported instead of
+// recursing forever.
+func redoOne(name, uuid string, visiting map[string]bool) (ok bool) {
+	runKey := uuid + ":" + name
+
+	targetsMu.Lock()
+	alreadyBuilt := built[runKey]
+	target, found := targets[name]
+	targetsMu.Unlock()
+
+	if alreadyBuilt {
+		return true
+	}
+	if !found {
+		Error.Printf("Redo: unknown target %q\n", name)
+		return false
+	}
+
+	if visiting[name] {
+		Error.Printf("Redo: dependency cycle detected on target %q\n", name)
+		return false
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	// A dep that names another target is itself redone first, so building
+	// name always sees that prerequisite's latest output.
+	for _, dep := range target.deps {
+		targetsMu.Lock()
+		_, depIsTarget := targets[dep.Path]
+		targetsMu.Unlock()
+		if depIsTarget && !redoOne(dep.Path, uuid, visiting) {
+			return false
+		}
+	}
+
+	lock, err := acquireLock(name)
+	if err != nil {
+		Error.Printf("Redo: could not lock target %q: %v\n", name, err)
+		return false
+	}
+	defer releaseLock(lock)
+
+	if needsRebuild(name, target.deps) {
+		Info.Printf("Redo: building %q\n", name)
+		if !target.build.Run() {
+			return false
+		}
+		if err := writeRecord(name, target.deps); err != nil {
+			Error.Printf("Redo: could not write record for %q: %v\n", name, err)
+			return false
+		}
+	}
+
+	targetsMu.Lock()
+	built[runKey] = true
+	targetsMu.Unlock()
+
+	return true
+}
+
+func needsRebuild(name string, deps []Dep) bool {
+	if _, err := os.Stat(name); err != nil {
+		return true
+	}
+
+	prev, err := readRecord(name)
+	if err != nil {
+		return true
+	}
+
+	prevByPath := make(map[string]depRecord, len(prev))
+	for _, r := range prev {
+		prevByPath[r.path] = r
+	}
+
+	for _, dep := range deps {
+		r, recorded := prevByPath[dep.Path]
+		switch dep.Kind {
+		case IfCreate:
+			if !recorded {
+				if _, err := os.Stat(dep.Path); err == nil {
+					return true
+				}
+			}
+		default: // IfChange
+			ctime, err := fileCtime(dep.Path)
+			if err != nil || !recorded {
+				return true
+			}
+			if r.ctime == ctime {
+				// mtime hasn't moved since the last build: skip the hash.
+				continue
+			}
+			hash, err := hashFile(dep.Path)
+			if err != nil || r.hash != hash {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type depRecord struct {
+	kind  DepKind
+	path  string
+	hash  string
+	ctime string
+}
+
+// writeRecord persists each prerequisite's content digest and mtime for
+// name as a recfile-style list of key/value blocks under .mago/<name>.rec.
+// The stored Ctime lets a later needsRebuild skip re-hashing a dep whose
+// mtime hasn't moved.
+func writeRecord(name string, deps []Dep) error {
+	if err := os.MkdirAll(recDir, 0755); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, dep := range deps {
+		hash := ""
+		ctime, err := fileCtime(dep.Path)
+		if dep.Kind == IfCreate {
+			if err != nil {
+				// Still absent: stay unrecorded so needsRebuild keeps
+				// checking for it to appear, instead of recording it as a
+				// permanent no-op.
+				continue
+			}
+		} else {
+			if err != nil {
+				return err
+			}
+			h, err := hashFile(dep.Path)
+			if err != nil {
+				return err
+			}
+			hash = h
+		}
+		fmt.Fprintf(&b, "Type: %s\nPath: %s\nHash: %s\nCtime: %s\n\n", dep.Kind, dep.Path, hash, ctime)
+	}
+
+	return os.WriteFile(recPath(name), []byte(b.String()), 0644)
+}
+
+func readRecord(name string) ([]depRecord, error) {
+	data, err := os.ReadFile(recPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []depRecord
+	for _, block := range strings.Split(string(data), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		var r depRecord
+		for _, line := range strings.Split(block, "\n") {
+			key, value, found := strings.Cut(line, ": ")
+			if !found {
+				continue
+			}
+			switch key {
+			case "Type":
+				if value == "ifcreate" {
+					r.kind = IfCreate
+				} else {
+					r.kind = IfChange
+				}
+			case "Path":
+				r.path = value
+			case "Hash":
+				r.hash = value
+			case "Ctime":
+				r.ctime = value
+			}
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fileCtime returns path's mtime as a string, used as a cheap pre-check
+// before hashing: if it matches the value from the last build, the dep is
+// assumed unchanged and hashFile is skipped.
+func fileCtime(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.ModTime().UnixNano(), 10), nil
+}
+
+func recPath(name string) string {
+	return filepath.Join(recDir, name+".rec")
+}
+
+func lockPath(name string) string {
+	return filepath.Join(recDir, name+".lock")
+}
+
+// acquireLock takes an exclusive, blocking lock on .mago/<name>.lock so
+// concurrent mago runs don't clobber each other's build records.
+func acquireLock(name string) (*os.File, error) {
+	if err := os.MkdirAll(recDir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(lockPath(name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func releaseLock(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	f.Close()
+}
+
+// inheritedBuildUUID is the BuildUUID this process was started with, e.g.
+// when it's itself a Cmd spawned by a parent mago run. Captured once at
+// package init, before buildUUID ever overwrites the environment variable.
+var inheritedBuildUUID = os.Getenv("BuildUUID")
+
+// buildUUID returns the BuildUUID for the current top-level Redo call: one
+// inherited from a parent mago process if this process was started as its
+// Cmd, or else a fresh one per call, propagated via the environment so
+// child Cmds started from here inherit it in turn.
+func buildUUID() string {
+	if inheritedBuildUUID != "" {
+		return inheritedBuildUUID
+	}
+	id := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	os.Setenv("BuildUUID", id)
+	return id
+}
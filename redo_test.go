@@ -0,0 +1,87 @@
+package mago
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type fakeRunnable struct {
+	ran *int
+	fn  func() bool
+}
+
+func (f fakeRunnable) Run() bool {
+	*f.ran++
+	if f.fn != nil {
+		return f.fn()
+	}
+	return true
+}
+
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+}
+
+func TestRedoIfCreateRebuildsOnceDepAppears(t *testing.T) {
+	chdirTemp(t)
+
+	runs := 0
+	target := NewTarget("out", nil, fakeRunnable{ran: &runs, fn: func() bool {
+		return os.WriteFile("out", []byte("built"), 0644) == nil
+	}})
+	target.IfCreate("trigger")
+
+	if !Redo("out") {
+		t.Fatal("first Redo failed")
+	}
+	if runs != 1 {
+		t.Fatalf("want 1 build after the initial Redo, got %d", runs)
+	}
+
+	if !Redo("out") {
+		t.Fatal("second Redo failed")
+	}
+	if runs != 1 {
+		t.Fatalf("want still 1 build before the ifcreate dep appears, got %d", runs)
+	}
+
+	if err := os.WriteFile("trigger", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !Redo("out") {
+		t.Fatal("third Redo failed")
+	}
+	if runs != 2 {
+		t.Fatalf("want 2 builds once the ifcreate dep appeared, got %d", runs)
+	}
+}
+
+func TestRedoDetectsDependencyCycle(t *testing.T) {
+	chdirTemp(t)
+
+	NewTarget("cycle-a", []string{"cycle-b"}, fakeRunnable{ran: new(int)})
+	NewTarget("cycle-b", []string{"cycle-a"}, fakeRunnable{ran: new(int)})
+
+	done := make(chan bool, 1)
+	go func() { done <- Redo("cycle-a") }()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("want Redo to fail on a dependency cycle")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Redo did not return — likely recursing forever on the cycle")
+	}
+}
@@ -0,0 +1,55 @@
+package mago
+
+import (
+	"flag"
+	"hash/fnv"
+)
+
+// NameableRunnable is a Runnable that can report a stable identifier for
+// itself, used by Shard (and the per-job logging in RunParallel) instead of
+// relying on job order.
+type NameableRunnable interface {
+	Runnable
+	Name() string
+}
+
+func (c Cmd) Name() string {
+	return c.String()
+}
+
+func (p PipedCmds) Name() string {
+	return p.String()
+}
+
+// Shard keeps only the jobs whose stable name hashes (FNV-1a) into index of
+// total, letting a large RunParallel invocation be split deterministically
+// across N CI machines.
+func Shard(jobs []Runnable, index, total int) []Runnable {
+	if total <= 1 {
+		return jobs
+	}
+
+	var sharded []Runnable
+	for _, job := range jobs {
+		if shardIndex(jobName(job), total) == index {
+			sharded = append(sharded, job)
+		}
+	}
+	return sharded
+}
+
+func shardIndex(name string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(total))
+}
+
+// ParseShardFlags registers and parses -shard/-shards, returning the index
+// of the shard to run and the total number of shards. Defaults to shard 0
+// of 1 (i.e. run everything) when unset.
+func ParseShardFlags() (index, total int) {
+	flag.IntVar(&index, "shard", 0, "index of the shard to run (0-based)")
+	flag.IntVar(&total, "shards", 1, "total number of shards")
+	flag.Parse()
+	return index, total
+}
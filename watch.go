@@ -0,0 +1,266 @@
+package mago
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Event is a single filesystem change reported by a Watcher.
+type Event = fsnotify.Event
+
+// Watcher reports filesystem changes matching patterns (and not matching
+// ignoredPatterns) using kernel notification (inotify/kqueue/
+// ReadDirectoryChangesW) instead of repeatedly walking the tree.
+type Watcher struct {
+	fsWatcher       *fsnotify.Watcher
+	patterns        []string
+	ignoredPatterns []string
+	events          chan Event
+	done            chan struct{}
+}
+
+// NewWatcher starts watching the current directory tree for files matching
+// patterns (and not ignoredPatterns), recursively adding watches for
+// directories as they're created.
+func NewWatcher(patterns, ignoredPatterns []string) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		fsWatcher:       fsWatcher,
+		patterns:        patterns,
+		ignoredPatterns: ignoredPatterns,
+		events:          make(chan Event),
+		done:            make(chan struct{}),
+	}
+
+	if err := w.addRecursive("."); err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// Events returns the channel of filesystem changes matching the Watcher's
+// patterns. It is closed when the Watcher is closed.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fsWatcher.Close()
+}
+
+func (w *Watcher) addRecursive(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if w.pathIgnored(path) {
+			return filepath.SkipDir
+		}
+		return w.fsWatcher.Add(path)
+	})
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addRecursive(event.Name)
+				}
+			}
+
+			if w.matches(event.Name) {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			Error.Printf("Watcher error: %v\n", err)
+		}
+	}
+}
+
+// matches reports whether path should surface as an Event, applying the
+// same per-path pattern/ignore checks WatchPoll uses against a full tree
+// walk. Unlike WatchPoll, files below an ignored directory never surface,
+// since addRecursive adds no watches inside it.
+func (w *Watcher) matches(path string) bool {
+	if w.pathIgnored(path) {
+		return false
+	}
+
+	name := filepath.Base(path)
+	for _, pattern := range w.patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) pathIgnored(path string) bool {
+	name := filepath.Base(path)
+	for _, ignoredPattern := range w.ignoredPatterns {
+		if matched, _ := filepath.Match(ignoredPattern, path); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(ignoredPattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch restarts name/args whenever a file matching patterns (and not
+// ignoredPatterns) changes, debouncing bursts of events by 200ms.
+func Watch(patterns, ignoredPatterns []string, name string, args ...string) {
+	WatchDebounce(200*time.Millisecond, patterns, ignoredPatterns, name, args...)
+}
+
+// WatchDebounce is Watch with a configurable debounce window. If the
+// underlying notification mechanism can't be started (e.g. an unsupported
+// filesystem), it falls back to WatchPoll.
+func WatchDebounce(debounce time.Duration, patterns, ignoredPatterns []string, name string, args ...string) {
+	watcher, err := NewWatcher(patterns, ignoredPatterns)
+	if err != nil {
+		Error.Printf("Could not start fsnotify watcher, falling back to polling: %v\n", err)
+		WatchPoll(patterns, ignoredPatterns, name, args...)
+		return
+	}
+	defer watcher.Close()
+
+	var mu sync.Mutex
+	cmd, _ := CmdAsync(name, args...)
+	restart := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		cmd.KillGroup()
+		cmd, _ = CmdAsync(name, args...)
+	}
+
+	var timer *time.Timer
+	for range watcher.Events() {
+		if timer == nil {
+			timer = time.AfterFunc(debounce, restart)
+		} else {
+			timer.Reset(debounce)
+		}
+	}
+}
+
+// WatchPoll is the polling fallback for filesystems without kernel change
+// notification support: it re-walks the tree every 100ms and stat-compares
+// against a temp file.
+func WatchPoll(patterns, ignoredPatterns []string, name string, args ...string) {
+	cmd, _ := CmdAsync(name, args...)
+	for {
+		if WatchFiles(patterns, ignoredPatterns) {
+			cmd.KillGroup()
+			cmd, _ = CmdAsync(name, args...)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func refreshWatchFile() (ok bool) {
+	var err error
+	watchFile, err = os.CreateTemp(os.TempDir(), "mago")
+	if err != nil {
+		Error.Printf("Could not create temp file for watch mode: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// WatchFiles walks the current directory tree looking for a file matching
+// patterns (and not ignoredPatterns) newer than the last call, the polling
+// primitive behind WatchPoll.
+func WatchFiles(patterns []string, ignoredPatterns []string) bool {
+	if watchFile == nil {
+		if !refreshWatchFile() {
+			return false
+		}
+	}
+
+	watchedFileChanged := false
+	err := filepath.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		for _, ignoredPattern := range ignoredPatterns {
+			ignoredPath, err := filepath.Match(ignoredPattern, path)
+			if err == nil && ignoredPath {
+				return nil
+			}
+			ignoredName, err := filepath.Match(ignoredPattern, info.Name())
+			if err == nil && ignoredName {
+				return nil
+			}
+		}
+
+		patternMatched := false
+		for _, pattern := range patterns {
+			matched, _ := filepath.Match(pattern, info.Name())
+			if matched {
+				patternMatched = true
+				break
+			}
+		}
+		if patternMatched {
+			watchFileInfo, err := watchFile.Stat()
+			if err != nil {
+				Error.Printf("Could not stat watch file: %v\n", err)
+				return fs.SkipAll
+			}
+			if info.ModTime().After(watchFileInfo.ModTime()) {
+				watchedFileChanged = true
+				refreshWatchFile()
+				return fs.SkipAll
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		Error.Printf("Could not walk current directory: %v\n", err)
+	}
+
+	return watchedFileChanged
+}
+
+var watchFile *os.File